@@ -1,58 +1,398 @@
 package provider
 
 import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform/plugin/convert"
+	"github.com/hashicorp/terraform/configschema"
 	"github.com/hashicorp/terraform/providers"
-	"github.com/vmihailenco/msgpack"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
 )
 
-// GRPCClient is an inmemory implementation of the TF GRPC
+// GRPCClient is an inmemory implementation of the TF GRPC provider
+// protocol. Rather than spawning the provider as a plugin subprocess and
+// talking to it over gRPC, it drives the provider's tfprotov5.ProviderServer
+// directly (obtained from schema.Provider.GRPCProvider(), the same handle
+// terraform-plugin-mux uses to bridge an SDKv2 provider in-process), which
+// lets terracognita read resources from upstream Terraform providers
+// without the overhead of the plugin transport.
 type GRPCClient struct {
 	NopProvider
-	server *schema.GRPCProviderServer
+	server tfprotov5.ProviderServer
+
+	schemaOnce sync.Once
+	mu         sync.Mutex
+	schemas    providers.GetSchemaResponse
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
+// NewGRPCClient builds a GRPCClient whose RPCs run against
+// context.Background, cancelled only by a later Stop or Close call. Use
+// NewGRPCClientWithContext to bind cancellation to a caller-owned root
+// context instead.
 func NewGRPCClient(pv *schema.Provider) *GRPCClient {
-	sv := schema.NewGRPCProviderServer(pv)
+	return NewGRPCClientWithContext(context.Background(), pv)
+}
+
+// NewGRPCClientWithContext builds a GRPCClient whose RPCs are bound to
+// ctx, so that cancelling ctx (or calling Stop/Close on the returned
+// client) interrupts any in-flight call to the wrapped provider.
+func NewGRPCClientWithContext(ctx context.Context, pv *schema.Provider) *GRPCClient {
+	return newGRPCClient(ctx, pv.GRPCProvider())
+}
+
+// newGRPCClient wraps an already-built tfprotov5.ProviderServer, which lets
+// NewClientForProvider in grpc_client_v6.go reuse it for providers it
+// discovers to speak protocol 5 by some means other than *schema.Provider.
+func newGRPCClient(ctx context.Context, server tfprotov5.ProviderServer) *GRPCClient {
+	ctx, cancel := context.WithCancel(ctx)
 	return &GRPCClient{
-		server: sv,
+		server: server,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Stop cancels the client's context, interrupting any in-flight RPC
+// against the wrapped provider, and calls the provider's own StopProvider
+// RPC so that an SDKv2 resource whose CRUD function selects on its
+// StopContext (rather than the context.Context threaded through these
+// RPCs) is interrupted too. Callers of a cancelled RPC see a diagnostic
+// tagged as a cancellation rather than a hang or a partial result.
+func (c *GRPCClient) Stop() error {
+	_, err := c.server.StopProvider(context.Background(), &tfprotov5.StopProviderRequest{})
+	c.cancel()
+	return err
+}
+
+// Close stops the client, same as Stop. The in-memory client has no
+// plugin subprocess to shut down, but implements Close for
+// providers.Interface compatibility.
+func (c *GRPCClient) Close() error {
+	return c.Stop()
+}
+
+// wrapErr tags err as a cancellation diagnostic when it happened because
+// the client's context was cancelled (via Stop or Close), so callers can
+// tell "the provider errored" apart from "we were told to stop".
+func (c *GRPCClient) wrapErr(err error) error {
+	if c.ctx.Err() != nil {
+		return fmt.Errorf("request cancelled: %w", err)
+	}
+	return err
+}
+
+// cancelledDiag reports the client's context as cancelled even when the
+// wrapped provider call returned normally, which is the common case: the
+// SDK's GRPCProviderServer does not abort its CRUD functions just because
+// ctx was cancelled, so a call that raced a Stop can still come back with
+// err == nil and a stale result. Checking here, right after every server
+// call, is what turns a Stop() mid-RPC into a cancellation diagnostic
+// instead of a result the caller might mistake for a successful read.
+func (c *GRPCClient) cancelledDiag() tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	if err := c.ctx.Err(); err != nil {
+		diags = diags.Append(fmt.Errorf("request cancelled: %w", err))
+	}
+	return diags
+}
+
+// GetSchema returns the complete schema for the provider. The schema is
+// only ever fetched from the wrapped provider once; subsequent calls
+// return the cached response, including any diagnostics produced by the
+// original fetch.
+func (c *GRPCClient) GetSchema() providers.GetSchemaResponse {
+	c.schemaOnce.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.schemas = c.fetchSchema()
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.schemas
+}
+
+// fetchSchema does the actual work of GetSchema; it must only be called
+// once, from within c.schemaOnce.
+func (c *GRPCClient) fetchSchema() providers.GetSchemaResponse {
+	var resp providers.GetSchemaResponse
+
+	protoResp, err := c.server.GetProviderSchema(c.ctx, &tfprotov5.GetProviderSchemaRequest{})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	resp.Provider, err = schemaFromProto(protoResp.Provider)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding provider schema: %w", err))
+		return resp
+	}
+	resp.ProviderMeta, err = schemaFromProto(protoResp.ProviderMeta)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding provider meta schema: %w", err))
+		return resp
+	}
+
+	resp.ResourceTypes = make(map[string]providers.Schema, len(protoResp.ResourceSchemas))
+	for name, s := range protoResp.ResourceSchemas {
+		resSchema, err := schemaFromProto(s)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding schema for resource %q: %w", name, err))
+			continue
+		}
+		resp.ResourceTypes[name] = resSchema
+	}
+
+	resp.DataSources = make(map[string]providers.Schema, len(protoResp.DataSourceSchemas))
+	for name, s := range protoResp.DataSourceSchemas {
+		dsSchema, err := schemaFromProto(s)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding schema for data source %q: %w", name, err))
+			continue
+		}
+		resp.DataSources[name] = dsSchema
 	}
+
+	return resp
+}
+
+// PrepareProviderConfig allows the provider to validate the configuration.
+func (c *GRPCClient) PrepareProviderConfig(r providers.PrepareProviderConfigRequest) providers.PrepareProviderConfigResponse {
+	var resp providers.PrepareProviderConfigResponse
+
+	schema := c.getSchema()
+	resp.Diagnostics = resp.Diagnostics.Append(schema.Diagnostics)
+	if schema.Diagnostics.HasErrors() {
+		return resp
+	}
+	ty := schema.Provider.Block.ImpliedType()
+
+	config, err := encodeDynamicValue(r.Config, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.PrepareProviderConfig(c.ctx, &tfprotov5.PrepareProviderConfigRequest{
+		Config: config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	preparedConfig, err := decodeDynamicValue(protoResp.PreparedConfig, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.PreparedConfig = preparedConfig
+
+	return resp
 }
 
-func (c *GRPCClient) ReadResource(r provider.ReadResourceRequest) provider.ReadResourceResponse {
-	logger.Trace("GRPCProvider: ReadResource")
+// ValidateResourceTypeConfig allows the provider to validate the resource
+// configuration values.
+func (c *GRPCClient) ValidateResourceTypeConfig(r providers.ValidateResourceTypeConfigRequest) providers.ValidateResourceTypeConfigResponse {
+	var resp providers.ValidateResourceTypeConfigResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+
+	config, err := encodeDynamicValue(r.Config, resSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
 
-	resSchema := c.getResourceSchema(r.TypeName)
+	protoResp, err := c.server.ValidateResourceTypeConfig(c.ctx, &tfprotov5.ValidateResourceTypeConfigRequest{
+		TypeName: r.TypeName,
+		Config:   config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	return resp
+}
+
+// ValidateDataSourceConfig allows the provider to validate the data source
+// configuration values.
+func (c *GRPCClient) ValidateDataSourceConfig(r providers.ValidateDataSourceConfigRequest) providers.ValidateDataSourceConfigResponse {
+	var resp providers.ValidateDataSourceConfigResponse
+
+	dsSchema, diags := c.getDatasourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+
+	config, err := encodeDynamicValue(r.Config, dsSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.ValidateDataSourceConfig(c.ctx, &tfprotov5.ValidateDataSourceConfigRequest{
+		TypeName: r.TypeName,
+		Config:   config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	return resp
+}
+
+// UpgradeResourceState is called when the state loader encounters an
+// instance state whose schema version is less than the one reported by the
+// currently-used version of the corresponding provider, and the upgraded
+// result is used for any further processing.
+func (c *GRPCClient) UpgradeResourceState(r providers.UpgradeResourceStateRequest) providers.UpgradeResourceStateResponse {
+	var resp providers.UpgradeResourceStateResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+
+	protoResp, err := c.server.UpgradeResourceState(c.ctx, &tfprotov5.UpgradeResourceStateRequest{
+		TypeName: r.TypeName,
+		Version:  int64(r.Version),
+		RawState: &tfprotov5.RawState{JSON: r.RawStateJSON},
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValue(protoResp.UpgradedState, resSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.UpgradedState = state
+
+	return resp
+}
+
+// Configure configures and initialized the provider.
+func (c *GRPCClient) Configure(r providers.ConfigureRequest) providers.ConfigureResponse {
+	var resp providers.ConfigureResponse
+
+	schema := c.getSchema()
+	resp.Diagnostics = resp.Diagnostics.Append(schema.Diagnostics)
+	if schema.Diagnostics.HasErrors() {
+		return resp
+	}
+
+	config, err := encodeDynamicValue(r.Config, schema.Provider.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.ConfigureProvider(c.ctx, &tfprotov5.ConfigureProviderRequest{
+		TerraformVersion: r.TerraformVersion,
+		Config:           config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	return resp
+}
+
+// ReadResource refreshes a resource and returns its current state.
+func (c *GRPCClient) ReadResource(r providers.ReadResourceRequest) providers.ReadResourceResponse {
+	var resp providers.ReadResourceResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
 	metaSchema := c.getProviderMetaSchema()
 
-	mp, err := msgpack.Marshal(r.PriorState, resSchema.Block.ImpliedType())
+	priorState, err := encodeDynamicValue(r.PriorState, resSchema.Block.ImpliedType())
 	if err != nil {
 		resp.Diagnostics = resp.Diagnostics.Append(err)
 		return resp
 	}
 
-	protoReq := &proto.ReadResource_Request{
+	protoReq := &tfprotov5.ReadResourceRequest{
 		TypeName:     r.TypeName,
-		CurrentState: &proto.DynamicValue{Msgpack: mp},
+		CurrentState: priorState,
 		Private:      r.Private,
 	}
 
 	if metaSchema.Block != nil {
-		metaMP, err := msgpack.Marshal(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		providerMeta, err := encodeDynamicValue(r.ProviderMeta, metaSchema.Block.ImpliedType())
 		if err != nil {
 			resp.Diagnostics = resp.Diagnostics.Append(err)
 			return resp
 		}
-		protoReq.ProviderMeta = &proto.DynamicValue{Msgpack: metaMP}
+		protoReq.ProviderMeta = providerMeta
 	}
 
-	protoResp, err := p.client.ReadResource(p.ctx, protoReq)
+	protoResp, err := c.server.ReadResource(c.ctx, protoReq)
 	if err != nil {
-		resp.Diagnostics = resp.Diagnostics.Append(grpcErr(err))
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
 		return resp
 	}
-	resp.Diagnostics = resp.Diagnostics.Append(convert.ProtoToDiagnostics(protoResp.Diagnostics))
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
 
 	state, err := decodeDynamicValue(protoResp.NewState, resSchema.Block.ImpliedType())
 	if err != nil {
@@ -65,38 +405,281 @@ func (c *GRPCClient) ReadResource(r provider.ReadResourceRequest) provider.ReadR
 	return resp
 }
 
-// getSchema is used internally to get the saved provider schema.  The schema
-// should have already been fetched from the provider, but we have to
-// synchronize access to avoid being called concurrently with GetSchema.
-func (p *GRPCClient) getSchema() providers.GetSchemaResponse {
-	c.mu.Lock()
-	// unlock inline in case GetSchema needs to be called
-	if c.schemas.Provider.Block != nil {
-		c.mu.Unlock()
-		return c.schemas
+// PlanResourceChange takes the current state and proposed state of a
+// resource, and returns the planned final state.
+func (c *GRPCClient) PlanResourceChange(r providers.PlanResourceChangeRequest) providers.PlanResourceChangeResponse {
+	var resp providers.PlanResourceChangeResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+	ty := resSchema.Block.ImpliedType()
+
+	priorState, err := encodeDynamicValue(r.PriorState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	proposedNewState, err := encodeDynamicValue(r.ProposedNewState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := encodeDynamicValue(r.Config, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov5.PlanResourceChangeRequest{
+		TypeName:         r.TypeName,
+		PriorState:       priorState,
+		ProposedNewState: proposedNewState,
+		Config:           config,
+		PriorPrivate:     r.PriorPrivate,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValue(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.PlanResourceChange(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValue(protoResp.PlannedState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.PlannedState = state
+	resp.PlannedPrivate = protoResp.PlannedPrivate
+	resp.LegacyTypeSystem = protoResp.UnsafeToUseLegacyTypeSystem
+
+	return resp
+}
+
+// ApplyResourceChange takes the planned state for a resource, which may
+// yet contain unknown computed values, and applies the changes returning
+// the final state.
+func (c *GRPCClient) ApplyResourceChange(r providers.ApplyResourceChangeRequest) providers.ApplyResourceChangeResponse {
+	var resp providers.ApplyResourceChangeResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+	ty := resSchema.Block.ImpliedType()
+
+	priorState, err := encodeDynamicValue(r.PriorState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	plannedState, err := encodeDynamicValue(r.PlannedState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := encodeDynamicValue(r.Config, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov5.ApplyResourceChangeRequest{
+		TypeName:       r.TypeName,
+		PriorState:     priorState,
+		PlannedState:   plannedState,
+		Config:         config,
+		PlannedPrivate: r.PlannedPrivate,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValue(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.ApplyResourceChange(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValue(protoResp.NewState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.NewState = state
+	resp.Private = protoResp.Private
+	resp.LegacyTypeSystem = protoResp.UnsafeToUseLegacyTypeSystem
+
+	return resp
+}
+
+// ImportResourceState requests that the given resource be imported.
+func (c *GRPCClient) ImportResourceState(r providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	var resp providers.ImportResourceStateResponse
+
+	protoResp, err := c.server.ImportResourceState(c.ctx, &tfprotov5.ImportResourceStateRequest{
+		TypeName: r.TypeName,
+		ID:       r.ID,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
 	}
-	c.mu.Unlock()
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	for _, imported := range protoResp.ImportedResources {
+		resSchema, diags := c.getResourceSchema(imported.TypeName)
+		if diags.HasErrors() {
+			resp.Diagnostics = resp.Diagnostics.Append(diags)
+			continue
+		}
 
-	// the schema should have been fetched already, but give it another shot
-	// just in case things are being called out of order. This may happen for
-	// tests.
-	schemas := p.GetSchema()
-	if schemas.Diagnostics.HasErrors() {
-		panic(schemas.Diagnostics.Err())
+		state, err := decodeDynamicValue(imported.State, resSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			continue
+		}
+
+		resp.ImportedResources = append(resp.ImportedResources, providers.ImportedResource{
+			TypeName: imported.TypeName,
+			State:    state,
+			Private:  imported.Private,
+		})
 	}
 
-	return schemas
+	return resp
 }
 
-// getResourceSchema is a helper to extract the schema for a resource, and
-// panics if the schema is not available.
-func (c *GRPCClient) getResourceSchema(name string) providers.Schema {
+// ReadDataSource returns the data source's current state.
+func (c *GRPCClient) ReadDataSource(r providers.ReadDataSourceRequest) providers.ReadDataSourceResponse {
+	var resp providers.ReadDataSourceResponse
+
+	dsSchema, diags := c.getDatasourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+
+	config, err := encodeDynamicValue(r.Config, dsSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov5.ReadDataSourceRequest{
+		TypeName: r.TypeName,
+		Config:   config,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValue(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.ReadDataSource(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProto(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValue(protoResp.State, dsSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.State = state
+
+	return resp
+}
+
+// getSchema is used internally to get the saved provider schema. It calls
+// GetSchema, which fetches the schema on first use and returns the cached
+// copy, under c.mu, on every call after that.
+func (c *GRPCClient) getSchema() providers.GetSchemaResponse {
+	return c.GetSchema()
+}
+
+// getResourceSchema is a helper to extract the schema for a resource. If
+// the initial schema fetch failed, or name is not a resource type this
+// provider knows about, it returns diagnostics describing the problem
+// instead of panicking, so callers can fold it into their response.
+func (c *GRPCClient) getResourceSchema(name string) (providers.Schema, tfdiags.Diagnostics) {
 	schema := c.getSchema()
+	if schema.Diagnostics.HasErrors() {
+		return providers.Schema{}, schema.Diagnostics
+	}
+
 	resSchema, ok := schema.ResourceTypes[name]
 	if !ok {
-		panic("unknown resource type " + name)
+		var diags tfdiags.Diagnostics
+		diags = diags.Append(fmt.Errorf("unknown resource type %q", name))
+		return providers.Schema{}, diags
 	}
-	return resSchema
+	return resSchema, nil
+}
+
+// getDatasourceSchema is a helper to extract the schema for a data
+// source. If the initial schema fetch failed, or name is not a data
+// source this provider knows about, it returns diagnostics describing the
+// problem instead of panicking.
+func (c *GRPCClient) getDatasourceSchema(name string) (providers.Schema, tfdiags.Diagnostics) {
+	schema := c.getSchema()
+	if schema.Diagnostics.HasErrors() {
+		return providers.Schema{}, schema.Diagnostics
+	}
+
+	dsSchema, ok := schema.DataSources[name]
+	if !ok {
+		var diags tfdiags.Diagnostics
+		diags = diags.Append(fmt.Errorf("unknown data source %q", name))
+		return providers.Schema{}, diags
+	}
+	return dsSchema, nil
 }
 
 // getProviderMetaSchema is a helper to extract the schema for the meta info
@@ -106,6 +689,150 @@ func (c *GRPCClient) getProviderMetaSchema() providers.Schema {
 	return schema.ProviderMeta
 }
 
+// encodeDynamicValue encodes val against ty the way the provider protocol
+// expects, preferring msgpack and falling back to JSON when the msgpack
+// encoding fails (e.g. for values decoded from a JSON-only state blob that
+// don't round-trip cleanly through msgpack).
+func encodeDynamicValue(val cty.Value, ty cty.Type) (*tfprotov5.DynamicValue, error) {
+	mp, err := msgpack.Marshal(val, ty)
+	if err == nil {
+		return &tfprotov5.DynamicValue{MsgPack: mp}, nil
+	}
+
+	js, jsErr := ctyjson.Marshal(val, ty)
+	if jsErr != nil {
+		return nil, err
+	}
+	return &tfprotov5.DynamicValue{JSON: js}, nil
+}
+
+// decodeDynamicValue decodes a DynamicValue returned by the provider back
+// into a cty.Value of the given type, preferring msgpack and falling back
+// to JSON.
+func decodeDynamicValue(v *tfprotov5.DynamicValue, ty cty.Type) (cty.Value, error) {
+	if v == nil {
+		return cty.NullVal(ty), nil
+	}
+
+	if len(v.MsgPack) > 0 {
+		return msgpack.Unmarshal(v.MsgPack, ty)
+	}
+	if len(v.JSON) > 0 {
+		return ctyjson.Unmarshal(v.JSON, ty)
+	}
+	return cty.NullVal(ty), nil
+}
+
+// diagnosticsFromProto converts the tfprotov5 diagnostics returned by a
+// provider RPC into tfdiags.Diagnostics. It is the tfprotov5 analogue of
+// terraform core's plugin/convert.ProtoToDiagnostics, which only knows how
+// to decode terraform's own internal proto types and so can't be reused
+// against the public tfprotov5 types the in-memory client deals in.
+func diagnosticsFromProto(protoDiags []*tfprotov5.Diagnostic) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	for _, d := range protoDiags {
+		if d == nil {
+			continue
+		}
+		severity := tfdiags.Error
+		if d.Severity == tfprotov5.DiagnosticSeverityWarning {
+			severity = tfdiags.Warning
+		}
+		diags = diags.Append(tfdiags.Sourceless(severity, d.Summary, d.Detail))
+	}
+	return diags
+}
+
+// schemaFromProto converts a tfprotov5.Schema into a providers.Schema,
+// walking its block and nested blocks into the equivalent
+// *configschema.Block. It is the tfprotov5 analogue of
+// plugin/convert.ProtoToProviderSchema, needed for the same reason as
+// diagnosticsFromProto above.
+func schemaFromProto(s *tfprotov5.Schema) (providers.Schema, error) {
+	if s == nil || s.Block == nil {
+		return providers.Schema{}, nil
+	}
+
+	block, err := blockFromProto(s.Block)
+	if err != nil {
+		return providers.Schema{}, err
+	}
+
+	return providers.Schema{
+		Version: s.Version,
+		Block:   block,
+	}, nil
+}
+
+func blockFromProto(b *tfprotov5.SchemaBlock) (*configschema.Block, error) {
+	block := &configschema.Block{
+		Attributes: make(map[string]*configschema.Attribute, len(b.Attributes)),
+		BlockTypes: make(map[string]*configschema.NestedBlock, len(b.BlockTypes)),
+	}
+
+	for _, a := range b.Attributes {
+		if a.Type == nil {
+			return nil, fmt.Errorf("attribute %q: nested-type attributes are not supported", a.Name)
+		}
+		ty, err := ctyTypeFromTFType(a.Type)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", a.Name, err)
+		}
+		block.Attributes[a.Name] = &configschema.Attribute{
+			Type:        ty,
+			Description: a.Description,
+			Required:    a.Required,
+			Optional:    a.Optional,
+			Computed:    a.Computed,
+			Sensitive:   a.Sensitive,
+		}
+	}
+
+	for _, nb := range b.BlockTypes {
+		nested, err := blockFromProto(nb.Block)
+		if err != nil {
+			return nil, fmt.Errorf("block %q: %w", nb.TypeName, err)
+		}
+		block.BlockTypes[nb.TypeName] = &configschema.NestedBlock{
+			Block:    *nested,
+			Nesting:  nestingModeFromProto(nb.Nesting),
+			MinItems: int(nb.MinItems),
+			MaxItems: int(nb.MaxItems),
+		}
+	}
+
+	return block, nil
+}
+
+func nestingModeFromProto(m tfprotov5.SchemaNestedBlockNestingMode) configschema.NestingMode {
+	switch m {
+	case tfprotov5.SchemaNestedBlockNestingModeGroup:
+		return configschema.NestingGroup
+	case tfprotov5.SchemaNestedBlockNestingModeList:
+		return configschema.NestingList
+	case tfprotov5.SchemaNestedBlockNestingModeSet:
+		return configschema.NestingSet
+	case tfprotov5.SchemaNestedBlockNestingModeMap:
+		return configschema.NestingMap
+	default:
+		return configschema.NestingSingle
+	}
+}
+
+// ctyTypeFromTFType recovers a cty.Type from the tftypes.Type tfprotov5
+// schemas describe attributes with. Both type systems serialize a type
+// constraint to the same JSON wire representation defined by the plugin
+// protocol (e.g. ["object",{"id":"string"}]), so round-tripping through
+// that JSON is sufficient to cross from one type system to the other
+// without reimplementing the structural walk by hand.
+func ctyTypeFromTFType(ty tftypes.Type) (cty.Type, error) {
+	raw, err := ty.MarshalJSON()
+	if err != nil {
+		return cty.NilType, err
+	}
+	return ctyjson.UnmarshalType(raw)
+}
+
 // -----
 // NopProvider is an empty implementation of the providers.Interface
 type NopProvider struct{}
@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fakeProviderServerV5 is a minimal tfprotov5.ProviderServer test double.
+// Embedding the (nil) interface satisfies every method of it, so a test
+// only has to override the handful it actually exercises; calling any
+// other method panics on the nil embedded value, which is fine since no
+// test here calls one it hasn't overridden.
+type fakeProviderServerV5 struct {
+	tfprotov5.ProviderServer
+
+	schema   *tfprotov5.GetProviderSchemaResponse
+	readResp *tfprotov5.ReadResourceResponse
+
+	// started/proceed let a test hold ReadResource open to race it
+	// against a concurrent Stop, the way a real SDKv2 CRUD function
+	// that ignores ctx would stay running past cancellation.
+	started chan struct{}
+	proceed chan struct{}
+}
+
+func (f *fakeProviderServerV5) GetProviderSchema(context.Context, *tfprotov5.GetProviderSchemaRequest) (*tfprotov5.GetProviderSchemaResponse, error) {
+	if f.schema == nil {
+		return &tfprotov5.GetProviderSchemaResponse{}, nil
+	}
+	return f.schema, nil
+}
+
+func (f *fakeProviderServerV5) ReadResource(ctx context.Context, req *tfprotov5.ReadResourceRequest) (*tfprotov5.ReadResourceResponse, error) {
+	if f.started != nil {
+		close(f.started)
+		<-f.proceed
+	}
+	if f.readResp != nil {
+		return f.readResp, nil
+	}
+	return &tfprotov5.ReadResourceResponse{NewState: req.CurrentState}, nil
+}
+
+func (f *fakeProviderServerV5) StopProvider(context.Context, *tfprotov5.StopProviderRequest) (*tfprotov5.StopProviderResponse, error) {
+	return &tfprotov5.StopProviderResponse{}, nil
+}
+
+// fakeProviderServerV6 is the tfprotov6 counterpart of fakeProviderServerV5.
+type fakeProviderServerV6 struct {
+	tfprotov6.ProviderServer
+
+	schema   *tfprotov6.GetProviderSchemaResponse
+	readResp *tfprotov6.ReadResourceResponse
+}
+
+func (f *fakeProviderServerV6) GetProviderSchema(context.Context, *tfprotov6.GetProviderSchemaRequest) (*tfprotov6.GetProviderSchemaResponse, error) {
+	if f.schema == nil {
+		return &tfprotov6.GetProviderSchemaResponse{}, nil
+	}
+	return f.schema, nil
+}
+
+func (f *fakeProviderServerV6) ReadResource(ctx context.Context, req *tfprotov6.ReadResourceRequest) (*tfprotov6.ReadResourceResponse, error) {
+	if f.readResp != nil {
+		return f.readResp, nil
+	}
+	return &tfprotov6.ReadResourceResponse{NewState: req.CurrentState}, nil
+}
+
+func TestGRPCClient_ReadResourceUnknownType(t *testing.T) {
+	pv := &schema.Provider{
+		ResourcesMap: map[string]*schema.Resource{},
+	}
+
+	c := NewGRPCClient(pv)
+
+	resp := c.ReadResource(providers.ReadResourceRequest{
+		TypeName: "does_not_exist",
+	})
+
+	if !resp.Diagnostics.HasErrors() {
+		t.Fatal("expected an error diagnostic for an unknown resource type, got none")
+	}
+}
+
+func TestGRPCClient_ReadDataSourceUnknownType(t *testing.T) {
+	pv := &schema.Provider{
+		DataSourcesMap: map[string]*schema.Resource{},
+	}
+
+	c := NewGRPCClient(pv)
+
+	resp := c.ReadDataSource(providers.ReadDataSourceRequest{
+		TypeName: "does_not_exist",
+	})
+
+	if !resp.Diagnostics.HasErrors() {
+		t.Fatal("expected an error diagnostic for an unknown data source type, got none")
+	}
+}
+
+func TestGRPCClient_StopCancelsContext(t *testing.T) {
+	pv := &schema.Provider{
+		ResourcesMap: map[string]*schema.Resource{},
+	}
+
+	c := NewGRPCClient(pv)
+
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop returned an error: %s", err)
+	}
+
+	if c.ctx.Err() == nil {
+		t.Fatal("expected the client's context to be cancelled after Stop")
+	}
+}
+
+// TestGRPCClient_StopCancelsInFlightRead drives a ReadResource against a
+// provider that ignores ctx entirely and returns normally regardless, the
+// same way a real SDKv2 CRUD function does. It asserts that Stop racing
+// that read still produces the cancellation diagnostic, via the
+// cancelledDiag check that runs after every successful server RPC - not
+// just the error path.
+func TestGRPCClient_StopCancelsInFlightRead(t *testing.T) {
+	fake := &fakeProviderServerV5{
+		schema: &tfprotov5.GetProviderSchemaResponse{
+			ResourceSchemas: map[string]*tfprotov5.Schema{
+				"thing_resource": {Block: &tfprotov5.SchemaBlock{}},
+			},
+		},
+		started: make(chan struct{}),
+		proceed: make(chan struct{}),
+	}
+	c := newGRPCClient(context.Background(), fake)
+
+	done := make(chan providers.ReadResourceResponse, 1)
+	go func() {
+		done <- c.ReadResource(providers.ReadResourceRequest{
+			TypeName:   "thing_resource",
+			PriorState: cty.EmptyObjectVal,
+		})
+	}()
+
+	<-fake.started
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop returned an error: %s", err)
+	}
+	close(fake.proceed)
+
+	resp := <-done
+	if !resp.Diagnostics.HasErrors() {
+		t.Fatal("expected a cancellation diagnostic for a read that raced Stop, got none")
+	}
+}
+
+func TestGRPCClientV6_ReadResourceUnknownType(t *testing.T) {
+	fake := &fakeProviderServerV6{}
+	c := NewGRPCClientV6(fake)
+
+	resp := c.ReadResource(providers.ReadResourceRequest{
+		TypeName: "does_not_exist",
+	})
+
+	if !resp.Diagnostics.HasErrors() {
+		t.Fatal("expected an error diagnostic for an unknown resource type, got none")
+	}
+}
+
+// TestGRPCClientV6_ReadResourceDeferred checks that a Deferred response
+// from the provider is translated into an error diagnostic instead of
+// being silently treated as a successful read.
+func TestGRPCClientV6_ReadResourceDeferred(t *testing.T) {
+	fake := &fakeProviderServerV6{
+		schema: &tfprotov6.GetProviderSchemaResponse{
+			ResourceSchemas: map[string]*tfprotov6.Schema{
+				"thing_resource": {Block: &tfprotov6.SchemaBlock{}},
+			},
+		},
+		readResp: &tfprotov6.ReadResourceResponse{
+			Deferred: &tfprotov6.Deferred{Reason: tfprotov6.DeferredReasonProviderConfigUnknown},
+		},
+	}
+	c := NewGRPCClientV6(fake)
+
+	resp := c.ReadResource(providers.ReadResourceRequest{
+		TypeName:   "thing_resource",
+		PriorState: cty.EmptyObjectVal,
+	})
+
+	if !resp.Diagnostics.HasErrors() {
+		t.Fatal("expected a deferred read to produce an error diagnostic, got none")
+	}
+}
+
+// TestNewClientForProvider_Dispatch checks that NewClientForProvider picks
+// the right client for each provider shape it supports, and reports a
+// diagnostic instead of panicking for anything else.
+func TestNewClientForProvider_Dispatch(t *testing.T) {
+	pv := &schema.Provider{ResourcesMap: map[string]*schema.Resource{}}
+	if _, ok := NewClientForProvider(pv).(*GRPCClient); !ok {
+		t.Fatal("expected a *schema.Provider to dispatch to GRPCClient")
+	}
+
+	if _, ok := NewClientForProvider(&fakeProviderServerV6{}).(*GRPCClientV6); !ok {
+		t.Fatal("expected a tfprotov6.ProviderServer to dispatch to GRPCClientV6")
+	}
+
+	if _, ok := NewClientForProvider(&fakeProviderServerV5{}).(*GRPCClient); !ok {
+		t.Fatal("expected a tfprotov5.ProviderServer to dispatch to GRPCClient")
+	}
+
+	resp := NewClientForProvider(42).GetSchema()
+	if !resp.Diagnostics.HasErrors() {
+		t.Fatal("expected an unsupported provider type to produce an error diagnostic instead of panicking")
+	}
+}
@@ -0,0 +1,918 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// GRPCClientV6 is an inmemory implementation of the TF GRPC provider
+// protocol v6. It mirrors GRPCClient but drives a tfprotov6.ProviderServer
+// directly, for providers that speak protocol 6 natively (terracognita's
+// own schema.Provider-based readers are always protocol 5 - see
+// NewClientForProvider below for how a v6 provider gets here).
+type GRPCClientV6 struct {
+	NopProvider
+	server tfprotov6.ProviderServer
+
+	schemaOnce sync.Once
+	mu         sync.Mutex
+	schemas    providers.GetSchemaResponse
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewGRPCClientV6 builds a GRPCClientV6 around an already-constructed
+// tfprotov6.ProviderServer, with RPCs run against context.Background,
+// cancelled only by a later Stop or Close call. Use
+// NewGRPCClientV6WithContext to bind cancellation to a caller-owned root
+// context instead.
+func NewGRPCClientV6(server tfprotov6.ProviderServer) *GRPCClientV6 {
+	return NewGRPCClientV6WithContext(context.Background(), server)
+}
+
+// NewGRPCClientV6WithContext builds a GRPCClientV6 whose RPCs are bound
+// to ctx, so that cancelling ctx (or calling Stop/Close on the returned
+// client) interrupts any in-flight call to the wrapped provider.
+func NewGRPCClientV6WithContext(ctx context.Context, server tfprotov6.ProviderServer) *GRPCClientV6 {
+	ctx, cancel := context.WithCancel(ctx)
+	return &GRPCClientV6{
+		server: server,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Stop calls the provider's own StopProvider RPC and cancels the client's
+// context. An in-flight RPC whose provider watches neither may still run
+// to completion; its result is then reported as cancelled by
+// cancelledDiag.
+func (c *GRPCClientV6) Stop() error {
+	_, err := c.server.StopProvider(context.Background(), &tfprotov6.StopProviderRequest{})
+	c.cancel()
+	return err
+}
+
+// Close stops the client, same as Stop.
+func (c *GRPCClientV6) Close() error {
+	return c.Stop()
+}
+
+// wrapErr tags err as a cancellation diagnostic when it happened because
+// the client's context was cancelled (via Stop or Close).
+func (c *GRPCClientV6) wrapErr(err error) error {
+	if c.ctx.Err() != nil {
+		return fmt.Errorf("request cancelled: %w", err)
+	}
+	return err
+}
+
+// cancelledDiag reports the client's context as cancelled even when the
+// wrapped provider call returned normally; see the v5 client's
+// cancelledDiag for why this check can't just live on the error path.
+func (c *GRPCClientV6) cancelledDiag() tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	if err := c.ctx.Err(); err != nil {
+		diags = diags.Append(fmt.Errorf("request cancelled: %w", err))
+	}
+	return diags
+}
+
+// NewClientForProvider inspects pv and returns the in-memory
+// providers.Interface appropriate for the protocol it speaks, bound to
+// context.Background. A *schema.Provider from terraform-plugin-sdk is
+// always protocol 5 - the SDK has no protocol 6 server of its own - so it
+// is always wrapped as a GRPCClient. Anything else is expected to already
+// be a tfprotov5.ProviderServer or tfprotov6.ProviderServer, which is the
+// shape providers built on terraform-plugin-go's protov6server (or framework
+// providers, or a v5 provider upgraded in advance with
+// tfprotov5/tf5to6server.UpgradeServer) present.
+func NewClientForProvider(pv interface{}) providers.Interface {
+	return NewClientForProviderWithContext(context.Background(), pv)
+}
+
+// NewClientForProviderWithContext is NewClientForProvider, but binds the
+// returned client's RPCs to ctx instead of context.Background, so that
+// cancelling ctx (or calling Stop/Close on the returned client)
+// interrupts any in-flight call to the wrapped provider.
+func NewClientForProviderWithContext(ctx context.Context, pv interface{}) providers.Interface {
+	switch p := pv.(type) {
+	case *schema.Provider:
+		return NewGRPCClientWithContext(ctx, p)
+	case tfprotov6.ProviderServer:
+		return NewGRPCClientV6WithContext(ctx, p)
+	case tfprotov5.ProviderServer:
+		return newGRPCClient(ctx, p)
+	default:
+		return newUnsupportedProviderClient(pv)
+	}
+}
+
+// unsupportedProviderClient is returned by NewClientForProvider(WithContext)
+// when pv doesn't implement any of the provider shapes it knows how to
+// drive. terracognita runs as a long-lived import tool against providers
+// it didn't choose, so a caller handing it something unusable needs a
+// diagnostic it can report and move past, not a panic that takes the
+// whole run down; every RPC reports the same error instead of silently
+// succeeding like the embedded NopProvider would.
+type unsupportedProviderClient struct {
+	NopProvider
+	err error
+}
+
+func newUnsupportedProviderClient(pv interface{}) *unsupportedProviderClient {
+	return &unsupportedProviderClient{
+		err: fmt.Errorf("provider: unsupported provider type %T", pv),
+	}
+}
+
+func (c *unsupportedProviderClient) diag() tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	diags = diags.Append(c.err)
+	return diags
+}
+
+func (c *unsupportedProviderClient) GetSchema() providers.GetSchemaResponse {
+	return providers.GetSchemaResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) PrepareProviderConfig(providers.PrepareProviderConfigRequest) providers.PrepareProviderConfigResponse {
+	return providers.PrepareProviderConfigResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) ValidateResourceTypeConfig(providers.ValidateResourceTypeConfigRequest) providers.ValidateResourceTypeConfigResponse {
+	return providers.ValidateResourceTypeConfigResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) ValidateDataSourceConfig(providers.ValidateDataSourceConfigRequest) providers.ValidateDataSourceConfigResponse {
+	return providers.ValidateDataSourceConfigResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) UpgradeResourceState(providers.UpgradeResourceStateRequest) providers.UpgradeResourceStateResponse {
+	return providers.UpgradeResourceStateResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) Configure(providers.ConfigureRequest) providers.ConfigureResponse {
+	return providers.ConfigureResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) ReadResource(providers.ReadResourceRequest) providers.ReadResourceResponse {
+	return providers.ReadResourceResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) PlanResourceChange(providers.PlanResourceChangeRequest) providers.PlanResourceChangeResponse {
+	return providers.PlanResourceChangeResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) ApplyResourceChange(providers.ApplyResourceChangeRequest) providers.ApplyResourceChangeResponse {
+	return providers.ApplyResourceChangeResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) ImportResourceState(providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	return providers.ImportResourceStateResponse{Diagnostics: c.diag()}
+}
+
+func (c *unsupportedProviderClient) ReadDataSource(providers.ReadDataSourceRequest) providers.ReadDataSourceResponse {
+	return providers.ReadDataSourceResponse{Diagnostics: c.diag()}
+}
+
+// GetSchema returns the complete schema for the provider. Protocol 6 also
+// advertises function schemas and server capabilities alongside the
+// resource/data source schemas, but providers.GetSchemaResponse (terraform
+// core's shape, shared with the v5 client) has no field for either, so
+// there's nothing to carry them in here; a caller that needs them should
+// talk to c.server directly.
+func (c *GRPCClientV6) GetSchema() providers.GetSchemaResponse {
+	c.schemaOnce.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.schemas = c.fetchSchema()
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.schemas
+}
+
+// fetchSchema does the actual work of GetSchema; it must only be called
+// once, from within c.schemaOnce.
+func (c *GRPCClientV6) fetchSchema() providers.GetSchemaResponse {
+	var resp providers.GetSchemaResponse
+
+	protoResp, err := c.server.GetProviderSchema(c.ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	resp.Provider, err = schemaFromProtoV6(protoResp.Provider)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding provider schema: %w", err))
+		return resp
+	}
+	resp.ProviderMeta, err = schemaFromProtoV6(protoResp.ProviderMeta)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding provider meta schema: %w", err))
+		return resp
+	}
+
+	resp.ResourceTypes = make(map[string]providers.Schema, len(protoResp.ResourceSchemas))
+	for name, s := range protoResp.ResourceSchemas {
+		resSchema, err := schemaFromProtoV6(s)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding schema for resource %q: %w", name, err))
+			continue
+		}
+		resp.ResourceTypes[name] = resSchema
+	}
+
+	resp.DataSources = make(map[string]providers.Schema, len(protoResp.DataSourceSchemas))
+	for name, s := range protoResp.DataSourceSchemas {
+		dsSchema, err := schemaFromProtoV6(s)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("decoding schema for data source %q: %w", name, err))
+			continue
+		}
+		resp.DataSources[name] = dsSchema
+	}
+
+	return resp
+}
+
+// ReadResource refreshes a resource and returns its current state. A
+// provider-reported Deferred is surfaced as a diagnostic rather than
+// silently returning the prior state, so callers know to skip the
+// resource instead of treating it as read.
+func (c *GRPCClientV6) ReadResource(r providers.ReadResourceRequest) providers.ReadResourceResponse {
+	var resp providers.ReadResourceResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+
+	priorState, err := encodeDynamicValueV6(r.PriorState, resSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov6.ReadResourceRequest{
+		TypeName:     r.TypeName,
+		CurrentState: priorState,
+		Private:      r.Private,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValueV6(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.ReadResource(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	if protoResp.Deferred != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(deferredDiagnostic(r.TypeName, protoResp.Deferred.Reason))
+		return resp
+	}
+
+	state, err := decodeDynamicValueV6(protoResp.NewState, resSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.NewState = state
+	resp.Private = protoResp.Private
+
+	return resp
+}
+
+// PrepareProviderConfig allows the provider to validate the configuration.
+func (c *GRPCClientV6) PrepareProviderConfig(r providers.PrepareProviderConfigRequest) providers.PrepareProviderConfigResponse {
+	var resp providers.PrepareProviderConfigResponse
+
+	schema := c.getSchema()
+	resp.Diagnostics = resp.Diagnostics.Append(schema.Diagnostics)
+	if schema.Diagnostics.HasErrors() {
+		return resp
+	}
+	ty := schema.Provider.Block.ImpliedType()
+
+	config, err := encodeDynamicValueV6(r.Config, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.PrepareProviderConfig(c.ctx, &tfprotov6.PrepareProviderConfigRequest{
+		Config: config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	preparedConfig, err := decodeDynamicValueV6(protoResp.PreparedConfig, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.PreparedConfig = preparedConfig
+
+	return resp
+}
+
+// ValidateResourceTypeConfig allows the provider to validate the resource
+// configuration values.
+func (c *GRPCClientV6) ValidateResourceTypeConfig(r providers.ValidateResourceTypeConfigRequest) providers.ValidateResourceTypeConfigResponse {
+	var resp providers.ValidateResourceTypeConfigResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+
+	config, err := encodeDynamicValueV6(r.Config, resSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.ValidateResourceConfig(c.ctx, &tfprotov6.ValidateResourceConfigRequest{
+		TypeName: r.TypeName,
+		Config:   config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	return resp
+}
+
+// ValidateDataSourceConfig allows the provider to validate the data source
+// configuration values.
+func (c *GRPCClientV6) ValidateDataSourceConfig(r providers.ValidateDataSourceConfigRequest) providers.ValidateDataSourceConfigResponse {
+	var resp providers.ValidateDataSourceConfigResponse
+
+	dsSchema, diags := c.getDatasourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+
+	config, err := encodeDynamicValueV6(r.Config, dsSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.ValidateDataResourceConfig(c.ctx, &tfprotov6.ValidateDataResourceConfigRequest{
+		TypeName: r.TypeName,
+		Config:   config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	return resp
+}
+
+// UpgradeResourceState is called when the state loader encounters an
+// instance state whose schema version is less than the one reported by the
+// currently-used version of the corresponding provider, and the upgraded
+// result is used for any further processing.
+func (c *GRPCClientV6) UpgradeResourceState(r providers.UpgradeResourceStateRequest) providers.UpgradeResourceStateResponse {
+	var resp providers.UpgradeResourceStateResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+
+	protoResp, err := c.server.UpgradeResourceState(c.ctx, &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: r.TypeName,
+		Version:  int64(r.Version),
+		RawState: &tfprotov6.RawState{JSON: r.RawStateJSON},
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValueV6(protoResp.UpgradedState, resSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.UpgradedState = state
+
+	return resp
+}
+
+// Configure configures and initialized the provider.
+func (c *GRPCClientV6) Configure(r providers.ConfigureRequest) providers.ConfigureResponse {
+	var resp providers.ConfigureResponse
+
+	schema := c.getSchema()
+	resp.Diagnostics = resp.Diagnostics.Append(schema.Diagnostics)
+	if schema.Diagnostics.HasErrors() {
+		return resp
+	}
+
+	config, err := encodeDynamicValueV6(r.Config, schema.Provider.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoResp, err := c.server.ConfigureProvider(c.ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: r.TerraformVersion,
+		Config:           config,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	return resp
+}
+
+// PlanResourceChange takes the current state and proposed state of a
+// resource, and returns the planned final state.
+func (c *GRPCClientV6) PlanResourceChange(r providers.PlanResourceChangeRequest) providers.PlanResourceChangeResponse {
+	var resp providers.PlanResourceChangeResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+	ty := resSchema.Block.ImpliedType()
+
+	priorState, err := encodeDynamicValueV6(r.PriorState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	proposedNewState, err := encodeDynamicValueV6(r.ProposedNewState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := encodeDynamicValueV6(r.Config, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         r.TypeName,
+		PriorState:       priorState,
+		ProposedNewState: proposedNewState,
+		Config:           config,
+		PriorPrivate:     r.PriorPrivate,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValueV6(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.PlanResourceChange(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValueV6(protoResp.PlannedState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.PlannedState = state
+	resp.PlannedPrivate = protoResp.PlannedPrivate
+	resp.LegacyTypeSystem = protoResp.UnsafeToUseLegacyTypeSystem
+
+	return resp
+}
+
+// ApplyResourceChange takes the planned state for a resource, which may
+// yet contain unknown computed values, and applies the changes returning
+// the final state.
+func (c *GRPCClientV6) ApplyResourceChange(r providers.ApplyResourceChangeRequest) providers.ApplyResourceChangeResponse {
+	var resp providers.ApplyResourceChangeResponse
+
+	resSchema, diags := c.getResourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+	ty := resSchema.Block.ImpliedType()
+
+	priorState, err := encodeDynamicValueV6(r.PriorState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	plannedState, err := encodeDynamicValueV6(r.PlannedState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	config, err := encodeDynamicValueV6(r.Config, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       r.TypeName,
+		PriorState:     priorState,
+		PlannedState:   plannedState,
+		Config:         config,
+		PlannedPrivate: r.PlannedPrivate,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValueV6(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.ApplyResourceChange(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValueV6(protoResp.NewState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.NewState = state
+	resp.Private = protoResp.Private
+	resp.LegacyTypeSystem = protoResp.UnsafeToUseLegacyTypeSystem
+
+	return resp
+}
+
+// ImportResourceState requests that the given resource be imported.
+func (c *GRPCClientV6) ImportResourceState(r providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	var resp providers.ImportResourceStateResponse
+
+	protoResp, err := c.server.ImportResourceState(c.ctx, &tfprotov6.ImportResourceStateRequest{
+		TypeName: r.TypeName,
+		ID:       r.ID,
+	})
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	for _, imported := range protoResp.ImportedResources {
+		resSchema, diags := c.getResourceSchema(imported.TypeName)
+		if diags.HasErrors() {
+			resp.Diagnostics = resp.Diagnostics.Append(diags)
+			continue
+		}
+
+		state, err := decodeDynamicValueV6(imported.State, resSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			continue
+		}
+
+		resp.ImportedResources = append(resp.ImportedResources, providers.ImportedResource{
+			TypeName: imported.TypeName,
+			State:    state,
+			Private:  imported.Private,
+		})
+	}
+
+	return resp
+}
+
+// ReadDataSource returns the data source's current state.
+func (c *GRPCClientV6) ReadDataSource(r providers.ReadDataSourceRequest) providers.ReadDataSourceResponse {
+	var resp providers.ReadDataSourceResponse
+
+	dsSchema, diags := c.getDatasourceSchema(r.TypeName)
+	resp.Diagnostics = resp.Diagnostics.Append(diags)
+	if diags.HasErrors() {
+		return resp
+	}
+	metaSchema := c.getProviderMetaSchema()
+
+	config, err := encodeDynamicValueV6(r.Config, dsSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+
+	protoReq := &tfprotov6.ReadDataSourceRequest{
+		TypeName: r.TypeName,
+		Config:   config,
+	}
+
+	if metaSchema.Block != nil {
+		providerMeta, err := encodeDynamicValueV6(r.ProviderMeta, metaSchema.Block.ImpliedType())
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+		protoReq.ProviderMeta = providerMeta
+	}
+
+	protoResp, err := c.server.ReadDataSource(c.ctx, protoReq)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(c.wrapErr(err))
+		return resp
+	}
+	if diags := c.cancelledDiag(); diags.HasErrors() {
+		resp.Diagnostics = resp.Diagnostics.Append(diags)
+		return resp
+	}
+	resp.Diagnostics = resp.Diagnostics.Append(diagnosticsFromProtoV6(protoResp.Diagnostics))
+
+	state, err := decodeDynamicValueV6(protoResp.State, dsSchema.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.State = state
+
+	return resp
+}
+
+// getSchema is used internally to get the saved provider schema.
+func (c *GRPCClientV6) getSchema() providers.GetSchemaResponse {
+	return c.GetSchema()
+}
+
+// getResourceSchema is a helper to extract the schema for a resource. If
+// the initial schema fetch failed, or name is not a resource type this
+// provider knows about, it returns diagnostics describing the problem
+// instead of panicking, so callers can fold it into their response.
+func (c *GRPCClientV6) getResourceSchema(name string) (providers.Schema, tfdiags.Diagnostics) {
+	schema := c.getSchema()
+	if schema.Diagnostics.HasErrors() {
+		return providers.Schema{}, schema.Diagnostics
+	}
+
+	resSchema, ok := schema.ResourceTypes[name]
+	if !ok {
+		var diags tfdiags.Diagnostics
+		diags = diags.Append(fmt.Errorf("unknown resource type %q", name))
+		return providers.Schema{}, diags
+	}
+	return resSchema, nil
+}
+
+// getDatasourceSchema is a helper to extract the schema for a data
+// source. If the initial schema fetch failed, or name is not a data
+// source this provider knows about, it returns diagnostics describing the
+// problem instead of panicking.
+func (c *GRPCClientV6) getDatasourceSchema(name string) (providers.Schema, tfdiags.Diagnostics) {
+	schema := c.getSchema()
+	if schema.Diagnostics.HasErrors() {
+		return providers.Schema{}, schema.Diagnostics
+	}
+
+	dsSchema, ok := schema.DataSources[name]
+	if !ok {
+		var diags tfdiags.Diagnostics
+		diags = diags.Append(fmt.Errorf("unknown data source %q", name))
+		return providers.Schema{}, diags
+	}
+	return dsSchema, nil
+}
+
+// getProviderMetaSchema is a helper to extract the schema for the meta
+// info defined for a provider,
+func (c *GRPCClientV6) getProviderMetaSchema() providers.Schema {
+	schema := c.getSchema()
+	return schema.ProviderMeta
+}
+
+// deferredDiagnostic turns a protocol v6 Deferred response into a
+// first-class diagnostic so that callers treat it the same way as any
+// other reason a resource couldn't be read, instead of having to special
+// case "Deferred" at every call site.
+func deferredDiagnostic(typeName string, reason tfprotov6.DeferredReason) error {
+	return deferredError{typeName: typeName, reason: reason}
+}
+
+type deferredError struct {
+	typeName string
+	reason   tfprotov6.DeferredReason
+}
+
+func (e deferredError) Error() string {
+	return fmt.Sprintf("provider deferred read of %s: %v", e.typeName, e.reason)
+}
+
+// encodeDynamicValueV6 is the protocol v6 counterpart of
+// encodeDynamicValue; the wire message differs by package but the
+// encoding rules are identical.
+func encodeDynamicValueV6(val cty.Value, ty cty.Type) (*tfprotov6.DynamicValue, error) {
+	mp, err := msgpack.Marshal(val, ty)
+	if err == nil {
+		return &tfprotov6.DynamicValue{MsgPack: mp}, nil
+	}
+
+	js, jsErr := ctyjson.Marshal(val, ty)
+	if jsErr != nil {
+		return nil, err
+	}
+	return &tfprotov6.DynamicValue{JSON: js}, nil
+}
+
+// decodeDynamicValueV6 is the protocol v6 counterpart of
+// decodeDynamicValue.
+func decodeDynamicValueV6(v *tfprotov6.DynamicValue, ty cty.Type) (cty.Value, error) {
+	if v == nil {
+		return cty.NullVal(ty), nil
+	}
+
+	if len(v.MsgPack) > 0 {
+		return msgpack.Unmarshal(v.MsgPack, ty)
+	}
+	if len(v.JSON) > 0 {
+		return ctyjson.Unmarshal(v.JSON, ty)
+	}
+	return cty.NullVal(ty), nil
+}
+
+// diagnosticsFromProtoV6 is the protocol v6 counterpart of
+// diagnosticsFromProto in grpc_client.go.
+func diagnosticsFromProtoV6(protoDiags []*tfprotov6.Diagnostic) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	for _, d := range protoDiags {
+		if d == nil {
+			continue
+		}
+		severity := tfdiags.Error
+		if d.Severity == tfprotov6.DiagnosticSeverityWarning {
+			severity = tfdiags.Warning
+		}
+		diags = diags.Append(tfdiags.Sourceless(severity, d.Summary, d.Detail))
+	}
+	return diags
+}
+
+// schemaFromProtoV6 is the protocol v6 counterpart of schemaFromProto in
+// grpc_client.go.
+func schemaFromProtoV6(s *tfprotov6.Schema) (providers.Schema, error) {
+	if s == nil || s.Block == nil {
+		return providers.Schema{}, nil
+	}
+
+	block, err := blockFromProtoV6(s.Block)
+	if err != nil {
+		return providers.Schema{}, err
+	}
+
+	return providers.Schema{
+		Version: s.Version,
+		Block:   block,
+	}, nil
+}
+
+func blockFromProtoV6(b *tfprotov6.SchemaBlock) (*configschema.Block, error) {
+	block := &configschema.Block{
+		Attributes: make(map[string]*configschema.Attribute, len(b.Attributes)),
+		BlockTypes: make(map[string]*configschema.NestedBlock, len(b.BlockTypes)),
+	}
+
+	for _, a := range b.Attributes {
+		if a.Type == nil {
+			return nil, fmt.Errorf("attribute %q: nested-type attributes are not supported", a.Name)
+		}
+		ty, err := ctyTypeFromTFType(a.Type)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", a.Name, err)
+		}
+		block.Attributes[a.Name] = &configschema.Attribute{
+			Type:        ty,
+			Description: a.Description,
+			Required:    a.Required,
+			Optional:    a.Optional,
+			Computed:    a.Computed,
+			Sensitive:   a.Sensitive,
+		}
+	}
+
+	for _, nb := range b.BlockTypes {
+		nested, err := blockFromProtoV6(nb.Block)
+		if err != nil {
+			return nil, fmt.Errorf("block %q: %w", nb.TypeName, err)
+		}
+		block.BlockTypes[nb.TypeName] = &configschema.NestedBlock{
+			Block:    *nested,
+			Nesting:  nestingModeFromProtoV6(nb.Nesting),
+			MinItems: int(nb.MinItems),
+			MaxItems: int(nb.MaxItems),
+		}
+	}
+
+	return block, nil
+}
+
+func nestingModeFromProtoV6(m tfprotov6.SchemaNestedBlockNestingMode) configschema.NestingMode {
+	switch m {
+	case tfprotov6.SchemaNestedBlockNestingModeGroup:
+		return configschema.NestingGroup
+	case tfprotov6.SchemaNestedBlockNestingModeList:
+		return configschema.NestingList
+	case tfprotov6.SchemaNestedBlockNestingModeSet:
+		return configschema.NestingSet
+	case tfprotov6.SchemaNestedBlockNestingModeMap:
+		return configschema.NestingMap
+	default:
+		return configschema.NestingSingle
+	}
+}